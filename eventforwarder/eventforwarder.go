@@ -4,10 +4,9 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/byuoitav/central-event-system/hub/base"
-	"github.com/byuoitav/central-event-system/messenger"
 	"github.com/byuoitav/common/log"
 	"github.com/byuoitav/common/v2/events"
 	"github.com/byuoitav/device-monitoring/localsystem"
@@ -19,15 +18,41 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	maxMessageSize = 512
-	pingPeriod     = 30 * time.Second
 )
 
+// pingPeriod is how often writeWebsocket pings each client to stop idle
+// proxies from closing the connection. It's a var rather than a const so
+// tests can shorten it instead of waiting out the real interval.
+var pingPeriod = 30 * time.Second
+
 var upgrader = websocket.Upgrader{}
 
 // Service contains the running config and dependencies for an instantiation of an eventforwarder service
 type Service struct {
-	wsClients map[*websocket.Conn]bool
+	wsClients map[*websocket.Conn]*clientState
 	clientMux sync.Mutex
+
+	// broker fans out every forwarded event to whichever transports
+	// (websocket, SSE, ...) have subscribed to it, and owns the resume
+	// ring SSE clients use to catch up on a reconnect.
+	broker *broker
+
+	// hub is the outbound connection to the central-event-system hub.
+	// reportWebSocketCount and any future outbound senders go through it
+	// instead of building their own messenger.
+	hub *HubClient
+
+	// sinks is everything ForwardEvent hands events off to. The ws/SSE
+	// broker is always one; the outbound hub connection only joins them
+	// if HUB_OUTBOUND_ADDRESS is configured (see New) - without a distinct
+	// outbound target, wiring hub in here would echo every event received
+	// from the hub straight back onto it.
+	sinks []Sink
+
+	// wsClientCount is the single source of truth for how many websocket
+	// clients are connected; reportWebSocketCount and wsClientGauge both
+	// read it instead of keeping their own counts.
+	wsClientCount int64
 }
 
 // New initializes a new eventforwarder service, connects to the hub, subscribes to the room events,
@@ -35,7 +60,19 @@ type Service struct {
 func New() *Service {
 	s := Service{}
 
-	s.wsClients = make(map[*websocket.Conn]bool, 1)
+	s.wsClients = make(map[*websocket.Conn]*clientState, 1)
+	s.broker = newBroker()
+	s.hub = NewHubClient(os.Getenv("HUB_ADDRESS"))
+	s.sinks = []Sink{s.broker}
+
+	// HUB_ADDRESS is also where the default EventSource reads events from
+	// (see NewEventSourceFromEnv), so forwarding every inbound event to
+	// s.hub unconditionally would echo it straight back onto the hub it
+	// came from. Only do so if a distinct outbound target is configured.
+	if addr := os.Getenv("HUB_OUTBOUND_ADDRESS"); addr != "" {
+		s.sinks = append(s.sinks, NewHubClient(addr))
+	}
+
 	go s.reportWebSocketCount()
 	return &s
 }
@@ -49,60 +86,98 @@ func (s *Service) HandleWebsocket(ctx echo.Context) error {
 		log.L.Errorf("Error while attempting to upgrade connection to websocket: %v", err)
 	}
 
+	sub := s.broker.subscribe()
+	state := &clientState{sub: sub}
+
 	s.clientMux.Lock()
-	s.wsClients[c] = true
-	go s.handleClose(c)
-	go s.pingWebSocket(c)
+	s.wsClients[c] = state
 	s.clientMux.Unlock()
+	s.setWSClientCount(atomic.AddInt64(&s.wsClientCount, 1))
+
+	go s.handleClose(c, state)
+	go s.writeWebsocket(c, state)
 
 	return nil
 }
 
-// ForwardEvent forwards the given event to all of the currently registered websocket clients
-func (s *Service) ForwardEvent(e events.Event) {
+// setWSClientCount keeps wsClientGauge in sync with wsClientCount.
+func (s *Service) setWSClientCount(n int64) {
+	wsClientGauge.Set(float64(n))
+}
 
-	if e.Key == "login" || e.Key == "card-read-error" {
+// writeWebsocket is the one goroutine allowed to write to c. It owns every
+// outbound operation on the connection - forwarded events as well as the
+// keep-alive pings - so that forwarding never has to coordinate writes
+// across goroutines. It returns (closing and removing the client) when the
+// subscriber is dropped, a write fails, or a ping fails.
+func (s *Service) writeWebsocket(c *websocket.Conn, state *clientState) {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
 
-		s.clientMux.Lock()
-		for c := range s.wsClients {
+	for {
+		select {
+		case entry, ok := <-state.sub.events:
+			if !ok {
+				return
+			}
 			c.SetWriteDeadline(time.Now().Add(writeWait))
-			err := c.WriteJSON(e)
+			start := time.Now()
+			err := c.WriteJSON(entry.event)
+			observeWriteLatency(state.sub, start)
 			if err != nil {
 				log.L.Errorf("Error while forwarding event to ws client: %s", err)
-				delete(s.wsClients, c)
-				c.WriteMessage(websocket.CloseMessage, []byte{})
-				c.Close()
+				s.removeWebsocket(c, state)
+				return
 			}
+		case <-ping.C:
+			c.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+				log.L.Errorf("Error while pinging ws client: %s", err)
+				s.removeWebsocket(c, state)
+				return
+			}
+		case <-state.sub.done:
+			// the subscriber was dropped out from under us, most likely
+			// because it was a slow consumer. close the connection rather
+			// than leaving it dangling.
+			s.removeWebsocket(c, state)
+			return
 		}
+	}
+}
+
+func (s *Service) removeWebsocket(c *websocket.Conn, state *clientState) {
+	s.broker.unsubscribe(state.sub)
+	wsWriteLatency.DeleteLabelValues(clientLabel(state.sub))
+
+	s.clientMux.Lock()
+	_, ok := s.wsClients[c]
+	delete(s.wsClients, c)
+	s.clientMux.Unlock()
 
-		s.clientMux.Unlock()
+	if ok {
+		s.setWSClientCount(atomic.AddInt64(&s.wsClientCount, -1))
 	}
 
+	c.WriteMessage(websocket.CloseMessage, []byte{})
+	c.Close()
 }
 
-// pingWebSocket pings the websocket every 30 seconds so that handleClose doesn't kill the connection
-// and open a new websocket when the read times out
-func (s *Service) pingWebSocket(c *websocket.Conn) {
-	ping := time.NewTicker(pingPeriod)
-	defer ping.Stop()
-	for {
-		select {
-		case <-ping.C:
-			_ = c.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
-				break
-			}
-		}
+// ForwardEvent hands the given event to every registered Sink. Today that's
+// the websocket/SSE broker (which further filters per client's subscription
+// - see subscriptionMessage) and, if HUB_OUTBOUND_ADDRESS is set, an
+// outbound HubClient; adding a new sink
+// doesn't require touching this method.
+func (s *Service) ForwardEvent(e events.Event) {
+	for _, sink := range s.sinks {
+		sink.Send(e)
 	}
 }
 
-// handleClose reads on the websocket until either it gets something or times out from the read deadline
-func (s *Service) handleClose(c *websocket.Conn) {
-	defer func() {
-		delete(s.wsClients, c)
-		c.WriteMessage(websocket.CloseMessage, []byte{})
-		c.Close()
-	}()
+// handleClose reads on the websocket until a read fails, either because the connection closed or the read deadline passed.
+// Any message received is interpreted as a subscription command that replaces the client's event filter.
+func (s *Service) handleClose(c *websocket.Conn, state *clientState) {
+	defer s.removeWebsocket(c, state)
 	c.SetReadLimit(maxMessageSize)
 	c.SetReadDeadline(time.Now().Add(pongWait))
 	c.SetPongHandler(func(string) error { c.SetReadDeadline(time.Now().Add(pongWait)); return nil })
@@ -114,21 +189,22 @@ func (s *Service) handleClose(c *websocket.Conn) {
 			}
 			break
 		}
-		log.L.Infof("Received message from socket: %s", msg)
+		state.applySubscription(msg)
 	}
 }
 
-// reportWebSocketCount sends an event with the number of open websockets
+// reportWebSocketCount sends an event with the number of open websockets.
+// It reads the same wsClientCount that drives wsClientGauge, so the event
+// and the /metrics endpoint never disagree about how many clients are
+// connected.
 func (s *Service) reportWebSocketCount() {
 	id := localsystem.MustSystemID()
 	deviceInfo := events.GenerateBasicDeviceInfo(id)
 	roomInfo := events.GenerateBasicRoomInfo(deviceInfo.RoomID)
-	messenger, err := messenger.BuildMessenger(os.Getenv("HUB_ADDRESS"), base.Messenger, 1000)
-	if err != nil {
-		log.L.Errorf("unable to build websocket count messenger: %s", err.Error())
-	}
+
 	for {
-		log.L.Debugf("sending websocket count of : %d", len(s.wsClients))
+		count := atomic.LoadInt64(&s.wsClientCount)
+		log.L.Debugf("sending websocket count of : %d", count)
 		countEvent := events.Event{
 			GeneratingSystem: id,
 			Timestamp:        time.Now(),
@@ -136,11 +212,9 @@ func (s *Service) reportWebSocketCount() {
 			TargetDevice:     deviceInfo,
 			AffectedRoom:     roomInfo,
 			Key:              "websocket-count",
-			Value:            fmt.Sprintf("%v", len(s.wsClients)),
-		}
-		if messenger != nil {
-			messenger.SendEvent(countEvent)
+			Value:            fmt.Sprintf("%v", count),
 		}
+		s.hub.SendEvent(countEvent)
 		time.Sleep(1 * time.Minute)
 	}
 }
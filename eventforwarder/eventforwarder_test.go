@@ -0,0 +1,75 @@
+package eventforwarder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWriteWebsocketDeregistersOnFailedPing asserts that writeWebsocket
+// returns (and removes the client) when a ping write fails, rather than
+// looping forever - the literal bug report this behavior fixed was a break
+// inside a select inside a for only exiting the select.
+func TestWriteWebsocketDeregistersOnFailedPing(t *testing.T) {
+	orig := pingPeriod
+	pingPeriod = 10 * time.Millisecond
+	defer func() { pingPeriod = orig }()
+
+	s := &Service{
+		wsClients: make(map[*websocket.Conn]*clientState),
+		broker:    newBroker(),
+	}
+
+	conns := make(chan *websocket.Conn, 1)
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %s", err)
+			return
+		}
+
+		state := &clientState{sub: s.broker.subscribe()}
+		s.clientMux.Lock()
+		s.wsClients[c] = state
+		s.clientMux.Unlock()
+
+		conns <- c
+		go func() {
+			s.writeWebsocket(c, state)
+			close(done)
+		}()
+	}))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+	client, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	defer client.Close()
+
+	// Close the server's own side of the connection directly so its next
+	// ping write is guaranteed to fail, rather than racing however the OS
+	// happens to propagate the client-side close.
+	c := <-conns
+	c.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writeWebsocket did not return after a failed ping write")
+	}
+
+	s.clientMux.Lock()
+	_, stillTracked := s.wsClients[c]
+	s.clientMux.Unlock()
+	if stillTracked {
+		t.Fatal("writeWebsocket failed to deregister the client after a failed ping write")
+	}
+}
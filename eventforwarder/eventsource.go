@@ -0,0 +1,54 @@
+package eventforwarder
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/byuoitav/common/v2/events"
+)
+
+// EventSource produces events for a Service to fan out to its sinks. The
+// BYU central-event-system hub is the source this package has always
+// pulled from; EventSource exists so other event buses (NATS, MQTT, ...)
+// can be dropped in behind it without the sink side (websocket, SSE, the
+// outbound hub connection) ever knowing the difference.
+type EventSource interface {
+	// Start begins producing events and returns a channel they arrive on.
+	// The channel is closed once ctx is done or the source can no longer
+	// produce events.
+	Start(ctx context.Context) (<-chan events.Event, error)
+}
+
+// NewEventSourceFromEnv picks an EventSource based on the EVENT_SOURCE
+// environment variable. It defaults to the BYU central-event-system hub,
+// which is what this service has always used.
+func NewEventSourceFromEnv() EventSource {
+	switch os.Getenv("EVENT_SOURCE") {
+	case "nats":
+		return NewNATSEventSource(os.Getenv("NATS_ADDRESS"), os.Getenv("NATS_SUBJECT"))
+	default:
+		return NewHubEventSource(os.Getenv("HUB_ADDRESS"))
+	}
+}
+
+// Run reads events from source until ctx is done or the source's channel
+// closes, forwarding each one to every sink exactly as ForwardEvent would.
+func (s *Service) Run(ctx context.Context, source EventSource) error {
+	in, err := source.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to start event source: %w", err)
+	}
+
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok {
+				return nil
+			}
+			s.ForwardEvent(e)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
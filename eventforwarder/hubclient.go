@@ -0,0 +1,208 @@
+package eventforwarder
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/byuoitav/central-event-system/hub/base"
+	"github.com/byuoitav/central-event-system/messenger"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/v2/events"
+)
+
+const (
+	hubClientQueueSize = 1000
+	hubPingInterval    = 30 * time.Second
+	hubMinBackoff      = 1 * time.Second
+	hubMaxBackoff      = 30 * time.Second
+)
+
+// HubClient maintains a persistent, auto-reconnecting connection to the BYU
+// central-event-system hub and lets callers push events to it without
+// having to think about whether the underlying connection is currently up.
+// reportWebSocketCount sends through one of these (Service.hub, built by
+// New) instead of a raw messenger.Messenger, so a hub that's unreachable at startup or drops
+// mid-run gets reconnected instead of going quiet for good.
+type HubClient struct {
+	addr string
+
+	queue chan events.Event
+	stop  chan struct{}
+	done  chan struct{}
+
+	reconnects uint64
+	dropped    uint64
+}
+
+// NewHubClient starts connecting to addr in the background and returns
+// immediately. Events sent before the first connection succeeds (or while
+// reconnecting) are buffered in the outbound queue.
+func NewHubClient(addr string) *HubClient {
+	hc := &HubClient{
+		addr:  addr,
+		queue: make(chan events.Event, hubClientQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go hc.run()
+	return hc
+}
+
+// SendEvent enqueues e to be sent to the hub. It never blocks indefinitely:
+// if the queue is full (the hub has been unreachable for a while) the event
+// is dropped and counted rather than stalling the caller.
+func (hc *HubClient) SendEvent(e events.Event) {
+	select {
+	case hc.queue <- e:
+	default:
+		atomic.AddUint64(&hc.dropped, 1)
+		log.L.Warnf("hub client queue full, dropping event with key %q", e.Key)
+	}
+}
+
+// Stop signals the client to stop reconnecting and waits for it to finish
+// the drain in progress, or for ctx to be done, whichever comes first.
+func (hc *HubClient) Stop(ctx context.Context) {
+	close(hc.stop)
+	select {
+	case <-hc.done:
+	case <-ctx.Done():
+	}
+}
+
+// Reconnects returns the number of times the client has (re)established a
+// connection to the hub.
+func (hc *HubClient) Reconnects() uint64 {
+	return atomic.LoadUint64(&hc.reconnects)
+}
+
+// QueueDepth returns the number of events currently buffered waiting to be
+// sent to the hub.
+func (hc *HubClient) QueueDepth() int {
+	return len(hc.queue)
+}
+
+// Dropped returns the number of events that were discarded because the
+// outbound queue was full.
+func (hc *HubClient) Dropped() uint64 {
+	return atomic.LoadUint64(&hc.dropped)
+}
+
+// run owns the reconnect loop. messenger.Messenger already retries its own
+// connection internally (see central-event-system/messenger.retryConnection),
+// so run's job is really just: get a usable messenger at all (handling the
+// case where BuildMessenger can't even start, e.g. no hub address yet), and
+// then keep an eye on its reported state so HubClient's own metrics and
+// outbound queue stay meaningful across a reconnect.
+func (hc *HubClient) run() {
+	defer close(hc.done)
+
+	backoff := hubMinBackoff
+	for {
+		select {
+		case <-hc.stop:
+			return
+		default:
+		}
+
+		m, err := messenger.BuildMessenger(hc.addr, base.Messenger, hubClientQueueSize)
+		if m == nil {
+			log.L.Errorf("unable to build hub messenger: %s", err)
+			if !hc.sleep(backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		if err != nil {
+			// BuildMessenger already started its own retry loop against
+			// the hub in the background; the messenger it handed back
+			// will heal itself, so there's nothing more for us to do here
+			// besides log it and keep using it.
+			log.L.Errorf("hub connection not yet established, messenger will retry on its own: %s", err)
+		}
+
+		atomic.AddUint64(&hc.reconnects, 1)
+		hubReconnectsGauge.Set(float64(hc.Reconnects()))
+		backoff = hubMinBackoff
+
+		hc.pump(m)
+		m.Kill()
+	}
+}
+
+// pump sends queued events to m until m reports its connection is down (in
+// which case run will throw it away and build a fresh one) or Stop is
+// called. There's no synchronous error from a failed write - SendEvent just
+// queues onto an internal channel - so dead-connection detection is done by
+// periodically polling GetState() instead.
+func (hc *HubClient) pump(m *messenger.Messenger) {
+	check := time.NewTicker(hubPingInterval)
+	defer check.Stop()
+
+	for {
+		select {
+		case e := <-hc.queue:
+			m.SendEvent(e)
+		case <-check.C:
+			if hubConnectionDown(m) {
+				log.L.Errorf("hub messenger reports its connection is down, rebuilding")
+				return
+			}
+		case <-hc.stop:
+			// drain whatever is still queued before handing back to run.
+			for {
+				select {
+				case e := <-hc.queue:
+					m.SendEvent(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// hubConnectionDown inspects messenger.GetState(), which reports "down"
+// once its read or write pump dies, regardless of whether it has since
+// kicked off its own retry.
+func hubConnectionDown(m *messenger.Messenger) bool {
+	state, ok := m.GetState().(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	s, ok := state["state"].(string)
+	if !ok {
+		return false
+	}
+
+	return strings.Contains(s, "down")
+}
+
+// sleep waits for d, returning false early if Stop is called.
+func (hc *HubClient) sleep(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-hc.stop:
+		return false
+	}
+}
+
+// nextBackoff doubles d (capped at hubMaxBackoff) and adds up to 20%
+// jitter, so that many instances reconnecting at once don't all hammer the
+// hub in lockstep.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > hubMaxBackoff {
+		d = hubMaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(d) / 5))
+	return d + jitter
+}
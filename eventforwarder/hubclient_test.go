@@ -0,0 +1,20 @@
+package eventforwarder
+
+import "testing"
+
+func TestNextBackoffCaps(t *testing.T) {
+	d := hubMinBackoff
+	for i := 0; i < 20; i++ {
+		d = nextBackoff(d)
+		if d > hubMaxBackoff+hubMaxBackoff/5 {
+			t.Fatalf("nextBackoff exceeded hubMaxBackoff plus jitter: got %s, max %s", d, hubMaxBackoff)
+		}
+	}
+}
+
+func TestNextBackoffGrows(t *testing.T) {
+	d := nextBackoff(hubMinBackoff)
+	if d <= hubMinBackoff {
+		t.Fatalf("nextBackoff did not grow past the starting backoff: got %s, started at %s", d, hubMinBackoff)
+	}
+}
@@ -0,0 +1,73 @@
+package eventforwarder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/byuoitav/central-event-system/hub/base"
+	"github.com/byuoitav/central-event-system/messenger"
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/v2/events"
+)
+
+// hubEventSource is the EventSource this package has always effectively
+// used: it subscribes to every event on the BYU central-event-system hub
+// and hands each one to Service.Run.
+type hubEventSource struct {
+	addr string
+}
+
+// NewHubEventSource returns an EventSource backed by the BYU
+// central-event-system hub at addr.
+func NewHubEventSource(addr string) EventSource {
+	return &hubEventSource{addr: addr}
+}
+
+func (h *hubEventSource) Start(ctx context.Context) (<-chan events.Event, error) {
+	m, err := messenger.BuildMessenger(h.addr, base.Messenger, 1000)
+	if m == nil {
+		return nil, fmt.Errorf("unable to build hub messenger: %w", err)
+	}
+	if err != nil {
+		// BuildMessenger already started its own retry loop against the
+		// hub in the background (see hubclient.go's run) and the
+		// messenger it handed back will heal itself once the hub is
+		// reachable, so there's nothing more to do here besides log it.
+		log.L.Errorf("hub connection not yet established, messenger will retry on its own: %s", err)
+	}
+
+	// "*" subscribes to every room's events rather than a specific list -
+	// the hub treats it as a wildcard (see central-event-system/repeater).
+	m.SubscribeToRooms("*")
+
+	out := make(chan events.Event)
+	go func() {
+		defer close(out)
+		defer m.Kill()
+
+		// ReceiveEvent blocks until the next event is available, so it has
+		// to run on its own goroutine for the select below to be able to
+		// notice ctx being done in the meantime.
+		received := make(chan events.Event)
+		go func() {
+			for {
+				received <- m.ReceiveEvent()
+			}
+		}()
+
+		for {
+			select {
+			case e := <-received:
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
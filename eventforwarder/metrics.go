@@ -0,0 +1,65 @@
+package eventforwarder
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	wsClientGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "lab_attendance",
+		Subsystem: "eventforwarder",
+		Name:      "websocket_clients",
+		Help:      "Number of currently connected websocket clients.",
+	})
+
+	eventsForwardedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "lab_attendance",
+		Subsystem: "eventforwarder",
+		Name:      "events_forwarded_total",
+		Help:      "Total number of events forwarded, labeled by event key.",
+	}, []string{"key"})
+
+	eventsDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "lab_attendance",
+		Subsystem: "eventforwarder",
+		Name:      "events_dropped_total",
+		Help:      "Total number of events dropped because a subscriber's queue was full.",
+	})
+
+	hubReconnectsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "lab_attendance",
+		Subsystem: "eventforwarder",
+		Name:      "hub_reconnects_total",
+		Help:      "Number of times the outbound hub connection has been (re)established.",
+	})
+
+	wsWriteLatency = promauto.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  "lab_attendance",
+		Subsystem:  "eventforwarder",
+		Name:       "websocket_write_latency_seconds",
+		Help:       "Latency of writes to each websocket client.",
+		Objectives: map[float64]float64{0.95: 0.01},
+	}, []string{"client"})
+)
+
+// HandleMetrics exposes the service's Prometheus metrics: current ws client
+// count, total events forwarded by key, events dropped due to slow
+// consumers, hub messenger reconnect count, and per-client write latency.
+func (s *Service) HandleMetrics(ctx echo.Context) error {
+	promhttp.Handler().ServeHTTP(ctx.Response().Writer, ctx.Request())
+	return nil
+}
+
+func clientLabel(sub *subscriber) string {
+	return strconv.FormatUint(sub.id, 10)
+}
+
+func observeWriteLatency(sub *subscriber, start time.Time) {
+	wsWriteLatency.WithLabelValues(clientLabel(sub)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,78 @@
+package eventforwarder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/v2/events"
+	"github.com/nats-io/nats.go"
+)
+
+// defaultNATSSubject is used when NATS_SUBJECT isn't set.
+const defaultNATSSubject = "events"
+
+// natsEventSource is an EventSource for deployments that don't run the BYU
+// central-event-system hub and would rather publish events over NATS.
+type natsEventSource struct {
+	addr    string
+	subject string
+}
+
+// NewNATSEventSource returns an EventSource backed by a NATS subject. An
+// empty subject falls back to defaultNATSSubject.
+func NewNATSEventSource(addr, subject string) EventSource {
+	if subject == "" {
+		subject = defaultNATSSubject
+	}
+
+	return &natsEventSource{addr: addr, subject: subject}
+}
+
+func (n *natsEventSource) Start(ctx context.Context) (<-chan events.Event, error) {
+	conn, err := nats.Connect(n.addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to nats: %w", err)
+	}
+
+	out := make(chan events.Event)
+
+	// inFlight tracks callbacks that have started but not yet returned, so
+	// the cleanup goroutine below can wait for them to finish before
+	// closing out. Unsubscribe/Close don't guarantee that on their own - a
+	// callback already running when they're called could still be trying
+	// to send on out after it's closed otherwise.
+	var inFlight sync.WaitGroup
+
+	sub, err := conn.Subscribe(n.subject, func(msg *nats.Msg) {
+		inFlight.Add(1)
+		defer inFlight.Done()
+
+		var e events.Event
+		if err := json.Unmarshal(msg.Data, &e); err != nil {
+			log.L.Errorf("unable to unmarshal event from nats subject %q: %s", n.subject, err)
+			return
+		}
+
+		select {
+		case out <- e:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to subscribe to nats subject %q: %w", n.subject, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+		conn.Close()
+		inFlight.Wait()
+		close(out)
+	}()
+
+	return out, nil
+}
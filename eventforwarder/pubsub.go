@@ -0,0 +1,168 @@
+package eventforwarder
+
+import (
+	"sync"
+
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/v2/events"
+)
+
+// subscriber receives a copy of every event that the broker accepts for it,
+// each tagged with the id the broker's ring assigned it. Transports
+// (websocket, SSE, ...) register a subscriber and read off of events until
+// done is closed; a plain websocket client just ignores the id.
+type subscriber struct {
+	id     uint64
+	events chan ringEntry
+	done   chan struct{}
+
+	filterMux sync.RWMutex
+	filter    *eventFilter
+}
+
+// eventFilter describes which events a subscriber is interested in. A nil
+// *eventFilter field on a subscriber falls back to defaultFilter, so clients
+// that never send a subscription message keep getting what they always got.
+type eventFilter struct {
+	keys    map[string]bool
+	rooms   map[string]bool
+	devices map[string]bool
+}
+
+// defaultFilter reproduces the hardcoded behavior ForwardEvent used to have
+// before per-client filtering existed.
+var defaultFilter = &eventFilter{
+	keys: map[string]bool{
+		"login":           true,
+		"card-read-error": true,
+	},
+}
+
+// matches reports whether e should be delivered to a subscriber with this
+// filter. An empty rooms/devices set means "don't filter on that field".
+func (f *eventFilter) matches(e events.Event) bool {
+	if len(f.keys) > 0 && !f.keys[e.Key] {
+		return false
+	}
+	if len(f.rooms) > 0 && !f.rooms[e.AffectedRoom.RoomID] {
+		return false
+	}
+	if len(f.devices) > 0 && !f.devices[e.TargetDevice.DeviceID] {
+		return false
+	}
+
+	return true
+}
+
+// setFilter replaces the subscriber's filter. Passing nil reverts it to
+// defaultFilter.
+func (s *subscriber) setFilter(f *eventFilter) {
+	s.filterMux.Lock()
+	defer s.filterMux.Unlock()
+	s.filter = f
+}
+
+func (s *subscriber) matches(e events.Event) bool {
+	s.filterMux.RLock()
+	f := s.filter
+	s.filterMux.RUnlock()
+
+	if f == nil {
+		f = defaultFilter
+	}
+	return f.matches(e)
+}
+
+// broker is a tiny internal pub/sub that decouples "where events come from"
+// (ForwardEvent) from "who wants a copy" (the websocket and SSE handlers).
+// Adding another transport is just a matter of calling subscribe and ranging
+// over the returned subscriber's events channel.
+type broker struct {
+	mux    sync.RWMutex
+	nextID uint64
+	subs   map[uint64]*subscriber
+
+	// ring holds the most recently published events so SSE clients can
+	// resume from a Last-Event-ID instead of missing whatever happened
+	// while they were disconnected. publish is the only thing that ever
+	// adds to it, so every event gets exactly one id regardless of how
+	// many (if any) subscribers are currently around to receive it.
+	ring *eventRing
+}
+
+func newBroker() *broker {
+	return &broker{
+		subs: make(map[uint64]*subscriber),
+		ring: newEventRing(sseRingSize),
+	}
+}
+
+// subscribe registers a new subscriber with the broker and returns it. The
+// caller must call unsubscribe once it's done reading, usually via defer.
+func (b *broker) subscribe() *subscriber {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.nextID++
+	sub := &subscriber{
+		id:     b.nextID,
+		events: make(chan ringEntry, 16),
+		done:   make(chan struct{}),
+	}
+	b.subs[sub.id] = sub
+
+	return sub
+}
+
+// unsubscribe removes a subscriber from the broker and closes its done
+// channel so any goroutines blocked on it can return.
+func (b *broker) unsubscribe(sub *subscriber) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if _, ok := b.subs[sub.id]; !ok {
+		return
+	}
+
+	delete(b.subs, sub.id)
+	close(sub.done)
+}
+
+// publish fans e out to every subscriber whose filter matches it. The send
+// to each subscriber's channel is non-blocking, so one slow consumer can
+// never stall delivery to the others. A subscriber whose channel is full is
+// assumed to be stuck and is dropped entirely rather than left to silently
+// miss events forever.
+//
+// e is added to the ring exactly once here, regardless of whether any
+// subscriber ends up matching it, so ring ids correspond 1:1 with distinct
+// published events instead of depending on how many SSE clients happen to
+// be connected.
+func (b *broker) publish(e events.Event) {
+	eventsForwardedTotal.WithLabelValues(e.Key).Inc()
+
+	entry := ringEntry{id: b.ring.add(e), event: e}
+
+	b.mux.RLock()
+	var overflowed []*subscriber
+	for _, sub := range b.subs {
+		if !sub.matches(e) {
+			continue
+		}
+
+		select {
+		case sub.events <- entry:
+		default:
+			overflowed = append(overflowed, sub)
+		}
+	}
+	b.mux.RUnlock()
+
+	// unsubscribe takes the write lock, so it has to happen after the read
+	// lock above is released.
+	for _, sub := range overflowed {
+		log.L.Errorf("subscriber %d queue full, dropping slow consumer", sub.id)
+		eventsDroppedTotal.Inc()
+		b.unsubscribe(sub)
+	}
+}
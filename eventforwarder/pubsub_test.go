@@ -0,0 +1,49 @@
+package eventforwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/byuoitav/common/v2/events"
+)
+
+// TestBrokerDropsSlowConsumer floods one subscriber's queue past its
+// capacity and asserts that publish drops it (and only it) rather than
+// blocking or silently wedging delivery to everyone else.
+func TestBrokerDropsSlowConsumer(t *testing.T) {
+	b := newBroker()
+
+	slow := b.subscribe()
+	fast := b.subscribe()
+	defer b.unsubscribe(fast)
+
+	// slow never reads, so once its queue fills publish has to either drop
+	// it or give up on fast too - only the former is acceptable. fast reads
+	// after every publish so its own queue never fills.
+	for i := 0; i < cap(slow.events)+1; i++ {
+		b.publish(events.Event{Key: "login"})
+		<-fast.events
+	}
+
+	select {
+	case <-slow.done:
+	case <-time.After(time.Second):
+		t.Fatal("expected slow subscriber to be unsubscribed after its queue filled")
+	}
+
+	b.mux.RLock()
+	_, stillSubscribed := b.subs[fast.id]
+	b.mux.RUnlock()
+	if !stillSubscribed {
+		t.Fatal("publish dropped fast alongside slow, but fast's queue was never full")
+	}
+
+	// A subsequent publish must still reach fast - the broker shouldn't
+	// have wedged itself trying to deal with the slow consumer.
+	b.publish(events.Event{Key: "login"})
+	select {
+	case <-fast.events:
+	case <-time.After(time.Second):
+		t.Fatal("fast subscriber stopped receiving events after slow was dropped")
+	}
+}
@@ -0,0 +1,21 @@
+package eventforwarder
+
+import "github.com/byuoitav/common/v2/events"
+
+// Sink is anything ForwardEvent can hand an event off to. The websocket/SSE
+// broker and the outbound HubClient both satisfy it, which is what lets
+// ForwardEvent treat "tell the dashboards" and "tell the hub" the same way.
+type Sink interface {
+	Send(e events.Event)
+}
+
+// Send makes broker satisfy Sink; it's just publish under a name that
+// matches the rest of the Sink implementations.
+func (b *broker) Send(e events.Event) {
+	b.publish(e)
+}
+
+// Send makes HubClient satisfy Sink.
+func (hc *HubClient) Send(e events.Event) {
+	hc.SendEvent(e)
+}
@@ -0,0 +1,140 @@
+package eventforwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/common/log"
+	"github.com/byuoitav/common/v2/events"
+	"github.com/labstack/echo"
+)
+
+// sseRingSize is the number of recently forwarded events kept around so that
+// a reconnecting SSE client can catch up via Last-Event-ID instead of just
+// picking up wherever the stream happens to be.
+const sseRingSize = 256
+
+// sseKeepAlive is how often a comment-only keep-alive is sent to SSE clients
+// to stop idle proxies from closing the connection.
+const sseKeepAlive = 15 * time.Second
+
+// eventRing is a small fixed-size ring buffer of the most recently forwarded
+// events, each tagged with a monotonically increasing id that doubles as the
+// SSE event id.
+type eventRing struct {
+	mux    sync.Mutex
+	nextID uint64
+	buf    []ringEntry
+}
+
+type ringEntry struct {
+	id    uint64
+	event events.Event
+}
+
+func newEventRing(size int) *eventRing {
+	return &eventRing{
+		buf: make([]ringEntry, 0, size),
+	}
+}
+
+// add appends e to the ring, evicting the oldest entry if the ring is full,
+// and returns the id assigned to it.
+func (r *eventRing) add(e events.Event) uint64 {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.nextID++
+	id := r.nextID
+
+	if len(r.buf) == cap(r.buf) {
+		// shift in place rather than reslicing, so the backing array (and
+		// with it the ring's size) stays fixed.
+		copy(r.buf, r.buf[1:])
+		r.buf = r.buf[:len(r.buf)-1]
+	}
+	r.buf = append(r.buf, ringEntry{id: id, event: e})
+
+	return id
+}
+
+// since returns every entry with an id greater than lastID, oldest first.
+func (r *eventRing) since(lastID uint64) []ringEntry {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var entries []ringEntry
+	for _, entry := range r.buf {
+		if entry.id > lastID {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// HandleSSE streams the same filtered events that HandleWebsocket forwards,
+// but over a plain text/event-stream response. This gives clients behind
+// proxies that won't allow a websocket upgrade a drop-in alternative without
+// duplicating any of the fan-out logic in ForwardEvent.
+func (s *Service) HandleSSE(ctx echo.Context) error {
+	resp := ctx.Response()
+	resp.Header().Set(echo.HeaderContentType, "text/event-stream")
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.Header().Set("Connection", "keep-alive")
+	resp.WriteHeader(200)
+
+	sub := s.broker.subscribe()
+	defer s.broker.unsubscribe(sub)
+
+	if lastID, err := strconv.ParseUint(ctx.Request().Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		for _, entry := range s.broker.ring.since(lastID) {
+			if !sub.matches(entry.event) {
+				continue
+			}
+			if err := s.writeSSEEvent(resp, entry.id, entry.event); err != nil {
+				return nil
+			}
+		}
+		resp.Flush()
+	}
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry, ok := <-sub.events:
+			if !ok {
+				return nil
+			}
+			if err := s.writeSSEEvent(resp, entry.id, entry.event); err != nil {
+				return nil
+			}
+			resp.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(resp, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			resp.Flush()
+		case <-ctx.Request().Context().Done():
+			return nil
+		case <-sub.done:
+			return nil
+		}
+	}
+}
+
+func (s *Service) writeSSEEvent(resp *echo.Response, id uint64, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.L.Errorf("unable to marshal event for sse client: %s", err)
+		return nil
+	}
+
+	_, err = fmt.Fprintf(resp, "id: %d\nevent: %s\ndata: %s\n\n", id, e.Key, payload)
+	return err
+}
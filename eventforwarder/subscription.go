@@ -0,0 +1,49 @@
+package eventforwarder
+
+import "encoding/json"
+
+// clientState is what's stored in Service.wsClients for each connected
+// websocket client: just enough to let handleClose update that client's
+// subscription as messages come in.
+type clientState struct {
+	sub *subscriber
+}
+
+// subscriptionMessage is the JSON shape a websocket client sends to change
+// what events it wants to receive, e.g.
+//
+//	{"subscribe":["login","card-read-error"],"rooms":["ITB-1101"],"devices":["ITB-1101-CP1"]}
+type subscriptionMessage struct {
+	Subscribe []string `json:"subscribe"`
+	Rooms     []string `json:"rooms"`
+	Devices   []string `json:"devices"`
+}
+
+// applySubscription parses msg as a subscriptionMessage and installs the
+// resulting filter on state's subscriber. Messages that aren't valid JSON
+// subscription commands are ignored.
+func (state *clientState) applySubscription(msg []byte) {
+	var sm subscriptionMessage
+	if err := json.Unmarshal(msg, &sm); err != nil {
+		return
+	}
+
+	f := &eventFilter{
+		keys:    toSet(sm.Subscribe),
+		rooms:   toSet(sm.Rooms),
+		devices: toSet(sm.Devices),
+	}
+	state.sub.setFilter(f)
+}
+
+func toSet(vals []string) map[string]bool {
+	if len(vals) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		set[v] = true
+	}
+	return set
+}
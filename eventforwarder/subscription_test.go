@@ -0,0 +1,79 @@
+package eventforwarder
+
+import (
+	"testing"
+
+	"github.com/byuoitav/common/v2/events"
+)
+
+func TestEventFilterMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *eventFilter
+		event  events.Event
+		want   bool
+	}{
+		{
+			name:   "empty filter matches anything",
+			filter: &eventFilter{},
+			event:  events.Event{Key: "login"},
+			want:   true,
+		},
+		{
+			name:   "key match",
+			filter: &eventFilter{keys: map[string]bool{"login": true}},
+			event:  events.Event{Key: "login"},
+			want:   true,
+		},
+		{
+			name:   "key mismatch",
+			filter: &eventFilter{keys: map[string]bool{"login": true}},
+			event:  events.Event{Key: "card-read-error"},
+			want:   false,
+		},
+		{
+			name:   "room match",
+			filter: &eventFilter{rooms: map[string]bool{"ITB-1101": true}},
+			event:  events.Event{AffectedRoom: events.BasicRoomInfo{RoomID: "ITB-1101"}},
+			want:   true,
+		},
+		{
+			name:   "room mismatch",
+			filter: &eventFilter{rooms: map[string]bool{"ITB-1101": true}},
+			event:  events.Event{AffectedRoom: events.BasicRoomInfo{RoomID: "ITB-1102"}},
+			want:   false,
+		},
+		{
+			name:   "device match",
+			filter: &eventFilter{devices: map[string]bool{"ITB-1101-CP1": true}},
+			event:  events.Event{TargetDevice: events.BasicDeviceInfo{DeviceID: "ITB-1101-CP1"}},
+			want:   true,
+		},
+		{
+			name:   "device mismatch",
+			filter: &eventFilter{devices: map[string]bool{"ITB-1101-CP1": true}},
+			event:  events.Event{TargetDevice: events.BasicDeviceInfo{DeviceID: "ITB-1101-CP2"}},
+			want:   false,
+		},
+		{
+			name: "must satisfy every configured dimension",
+			filter: &eventFilter{
+				keys:  map[string]bool{"login": true},
+				rooms: map[string]bool{"ITB-1101": true},
+			},
+			event: events.Event{
+				Key:          "login",
+				AffectedRoom: events.BasicRoomInfo{RoomID: "ITB-1102"},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(tt.event); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}